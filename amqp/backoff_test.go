@@ -0,0 +1,54 @@
+package amqp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffWaitGrowsExponentiallyUpToMax(t *testing.T) {
+	b := backoff{base: 100 * time.Millisecond, max: time.Second, jitter: 0}
+
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{2, 400 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second}, // 1600ms would exceed max
+		{10, time.Second},
+	}
+
+	for _, c := range cases {
+		if got := b.wait(c.attempt); got != c.want {
+			t.Errorf("wait(%d) = %s, want %s", c.attempt, got, c.want)
+		}
+	}
+}
+
+func TestBackoffWaitAppliesJitterWithinBounds(t *testing.T) {
+	b := backoff{base: time.Second, max: time.Minute, jitter: 0.2}
+
+	delta := time.Duration(float64(time.Second) * 0.2)
+	min := time.Second - delta/2
+	max := min + delta
+
+	for i := 0; i < 100; i++ {
+		got := b.wait(0)
+		if got < min || got > max {
+			t.Fatalf("wait(0) = %s, want within [%s, %s]", got, min, max)
+		}
+	}
+}
+
+func TestBackoffWaitZeroJitterIsDeterministic(t *testing.T) {
+	b := backoff{base: 50 * time.Millisecond, max: time.Second, jitter: 0}
+
+	first := b.wait(2)
+	second := b.wait(2)
+
+	if first != second {
+		t.Errorf("wait(2) returned %s then %s, want identical results with no jitter", first, second)
+	}
+}
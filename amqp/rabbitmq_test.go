@@ -0,0 +1,31 @@
+package amqp
+
+import (
+	"testing"
+
+	"github.com/devnw/atomizer"
+)
+
+func TestEffectiveBindingKeyDefaultsToQueueName(t *testing.T) {
+	r := &rabbitmq{}
+
+	if got := r.effectiveBindingKey("atoms.image"); got != "atoms.image" {
+		t.Errorf("effectiveBindingKey() = %q, want the queue's own name %q", got, "atoms.image")
+	}
+}
+
+func TestEffectiveBindingKeyUsesOverrideWhenSet(t *testing.T) {
+	r := &rabbitmq{bindingKey: "atoms.#"}
+
+	if got := r.effectiveBindingKey("atoms.image"); got != "atoms.#" {
+		t.Errorf("effectiveBindingKey() = %q, want the WithBindingKey override %q", got, "atoms.#")
+	}
+}
+
+func TestDefaultRoutingKeyFuncUsesAtomID(t *testing.T) {
+	e := atomizer.Electron{ID: "e1", AtomID: "atoms.image"}
+
+	if got := defaultRoutingKeyFunc(e); got != "atoms.image" {
+		t.Errorf("defaultRoutingKeyFunc() = %q, want electron's AtomID %q", got, "atoms.image")
+	}
+}
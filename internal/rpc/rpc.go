@@ -0,0 +1,102 @@
+// Package rpc holds the transport-agnostic request/reply plumbing shared by
+// the conductor implementations in this repository: encoding electrons and
+// their completed properties as JSON, and fanning completed results back
+// out to whichever Send call is waiting on them.
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/devnw/atomizer"
+)
+
+// MarshalElectron encodes an electron for transport.
+func MarshalElectron(e atomizer.Electron) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// UnmarshalElectron decodes an electron received from a transport.
+func UnmarshalElectron(body []byte) (atomizer.Electron, error) {
+	e := atomizer.Electron{}
+	err := json.Unmarshal(body, &e)
+	return e, err
+}
+
+// MarshalProperties encodes a completed electron's properties for transport.
+func MarshalProperties(p *atomizer.Properties) ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// Router dispatches completed atomizer.Properties results to the channel
+// returned from Send for the electron that produced them. Each conductor
+// backend is responsible for carrying some correlation identifier (an AMQP
+// CorrelationId, a NATS header, etc.) from the request through to the
+// result and passing it to Dispatch unchanged. It is safe for concurrent
+// use.
+type Router struct {
+	mu    sync.Mutex
+	chans map[string]chan<- *atomizer.Properties
+}
+
+// NewRouter returns a ready to use Router.
+func NewRouter() *Router {
+	return &Router{chans: make(map[string]chan<- *atomizer.Properties)}
+}
+
+// Register associates id with the channel Send is waiting on, prior to the
+// request going out over the wire.
+func (router *Router) Register(id string, ch chan<- *atomizer.Properties) {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+
+	router.chans[id] = ch
+}
+
+// Cancel removes and returns the channel registered for id, if any, without
+// dispatching a result to it. Callers use this to give up waiting on a
+// reply (e.g. because it timed out) and close the channel themselves.
+func (router *Router) Cancel(id string) (chan<- *atomizer.Properties, bool) {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+
+	c, ok := router.chans[id]
+	if ok {
+		delete(router.chans, id)
+	}
+
+	return c, ok
+}
+
+// Dispatch decodes body as atomizer.Properties and delivers it to the
+// channel registered under id, closing that channel once the result has
+// been pushed. If nothing is registered for id the result is dropped. An
+// error is returned when body cannot be decoded, or with ctx.Err() when ctx
+// is done before the result could be delivered.
+func (router *Router) Dispatch(ctx context.Context, id string, body []byte) error {
+	p := &atomizer.Properties{}
+	if err := json.Unmarshal(body, p); err != nil {
+		return err
+	}
+
+	router.mu.Lock()
+	c, ok := router.chans[id]
+	if ok {
+		delete(router.chans, id)
+	}
+	router.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	defer close(c)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c <- p:
+		return nil
+	}
+}
@@ -0,0 +1,138 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devnw/atomizer"
+)
+
+func TestRouterDispatchDeliversAndClosesChannel(t *testing.T) {
+	router := NewRouter()
+	ch := make(chan *atomizer.Properties, 1)
+
+	router.Register("e1", ch)
+
+	body, err := MarshalProperties(&atomizer.Properties{ElectronID: "e1"})
+	if err != nil {
+		t.Fatalf("MarshalProperties: %v", err)
+	}
+
+	if err := router.Dispatch(context.Background(), "e1", body); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	p, ok := <-ch
+	if !ok || p.ElectronID != "e1" {
+		t.Fatalf("got p=%v ok=%v, want properties for e1", p, ok)
+	}
+
+	if _, ok := <-ch; ok {
+		t.Fatal("channel was not closed after Dispatch")
+	}
+}
+
+func TestRouterDispatchUnregisteredIDIsDropped(t *testing.T) {
+	router := NewRouter()
+
+	body, err := MarshalProperties(&atomizer.Properties{ElectronID: "missing"})
+	if err != nil {
+		t.Fatalf("MarshalProperties: %v", err)
+	}
+
+	if err := router.Dispatch(context.Background(), "missing", body); err != nil {
+		t.Fatalf("Dispatch on unregistered id returned error: %v", err)
+	}
+}
+
+func TestRouterCancelRemovesWithoutDispatch(t *testing.T) {
+	router := NewRouter()
+	ch := make(chan *atomizer.Properties, 1)
+
+	router.Register("e1", ch)
+
+	got, ok := router.Cancel("e1")
+	if !ok || got != chan<- *atomizer.Properties(ch) {
+		t.Fatalf("Cancel returned got=%v ok=%v, want the registered channel", got, ok)
+	}
+
+	if _, ok := router.Cancel("e1"); ok {
+		t.Fatal("Cancel succeeded twice for the same id")
+	}
+
+	body, err := MarshalProperties(&atomizer.Properties{ElectronID: "e1"})
+	if err != nil {
+		t.Fatalf("MarshalProperties: %v", err)
+	}
+
+	if err := router.Dispatch(context.Background(), "e1", body); err != nil {
+		t.Fatalf("Dispatch after Cancel: %v", err)
+	}
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("Dispatch delivered to a channel already removed by Cancel")
+		}
+	default:
+	}
+}
+
+func TestRouterDispatchContextCanceled(t *testing.T) {
+	router := NewRouter()
+	ch := make(chan *atomizer.Properties)
+
+	router.Register("e1", ch)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	body, err := MarshalProperties(&atomizer.Properties{ElectronID: "e1"})
+	if err != nil {
+		t.Fatalf("MarshalProperties: %v", err)
+	}
+
+	if err := router.Dispatch(ctx, "e1", body); err == nil {
+		t.Fatal("Dispatch with a canceled ctx and no receiver returned nil error, want ctx.Err()")
+	}
+}
+
+func TestRouterRegisterDispatchCancelRace(t *testing.T) {
+	router := NewRouter()
+
+	const n = 200
+
+	var wg sync.WaitGroup
+	wg.Add(n * 2)
+
+	for i := 0; i < n; i++ {
+		id := string(rune('a' + i%26))
+		ch := make(chan *atomizer.Properties, 1)
+		router.Register(id, ch)
+
+		go func(id string) {
+			defer wg.Done()
+			body, _ := MarshalProperties(&atomizer.Properties{ElectronID: id})
+			_ = router.Dispatch(context.Background(), id, body)
+		}(id)
+
+		go func(id string) {
+			defer wg.Done()
+			router.Cancel(id)
+		}(id)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("concurrent Register/Dispatch/Cancel did not complete, possible deadlock")
+	}
+}
@@ -0,0 +1,279 @@
+// Package nats implements the atomizer.Conductor interface against NATS,
+// as a lightweight alternative to the amqp package for callers who don't
+// want to stand up a RabbitMQ broker.
+package nats
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/devnw/alog"
+	"github.com/devnw/atomizer"
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+
+	"github.com/benjivesterby/conductors/internal/rpc"
+)
+
+// CorrelationIDHeader carries the electron ID on the NATS message header so
+// the single shared reply inbox can dispatch a result back to the Send
+// call that's waiting on it, mirroring the role CorrelationId plays on the
+// amqp conductor.
+const CorrelationIDHeader = "Conductor-Correlation-Id"
+
+// Option configures optional behavior of the nats conductor returned from
+// Connect.
+type Option func(*natsmq)
+
+// WithTimeout bounds how long Send waits for a result before giving up on
+// an electron, unless the ctx passed to Send already carries its own
+// deadline. Disabled (no timeout beyond the caller's ctx) by default.
+func WithTimeout(d time.Duration) Option {
+	return func(n *natsmq) {
+		n.sendTimeout = d
+	}
+}
+
+// Connect establishes a NATS-backed atomizer.Conductor. Electrons are
+// published to subject and consumed by whichever conductor(s) subscribe to
+// it; this conductor's own per-instance inbox subject (nats.NewInbox()) is
+// used as the reply target for the electrons it sends.
+func Connect(ctx context.Context, url, subject string, options ...Option) (atomizer.Conductor, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if url == "" {
+		return nil, errors.New("empty connection string")
+	}
+
+	if subject == "" {
+		return nil, errors.New("empty subject")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	conn, err := nats.Connect(url, nats.RetryOnFailedConnect(true))
+	if err != nil {
+		defer cancel()
+		return nil, errors.Errorf("error connecting to nats | %s", err.Error())
+	}
+
+	n := &natsmq{
+		ctx:     ctx,
+		cancel:  cancel,
+		conn:    conn,
+		subject: subject,
+		inbox:   nats.NewInbox(),
+		router:  rpc.NewRouter(),
+	}
+
+	for _, o := range options {
+		o(n)
+	}
+
+	go n.cleanup()
+
+	return n, nil
+}
+
+// natsmq uses the nats.go client to send and receive from NATS in order to
+// atomize electrons.
+type natsmq struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	conn *nats.Conn
+
+	// subject is where electrons are published/consumed for processing.
+	subject string
+
+	// inbox is this conductor's exclusive reply subject. Results carry the
+	// originating electron's ID in CorrelationIDHeader so the single
+	// subscription below can fan them back out by id.
+	inbox   string
+	router  *rpc.Router
+	replyMu sync.Once
+
+	// sendTimeout bounds how long Send waits for a result when ctx has no
+	// deadline of its own; zero disables it.
+	sendTimeout time.Duration
+
+	// pending maps an in-flight electron ID to the inbound *nats.Msg that
+	// carried it, so Complete can reply directly to it.
+	pending sync.Map
+}
+
+func (n *natsmq) cleanup() {
+	<-n.ctx.Done()
+	n.conn.Close()
+}
+
+// Receive gets the atoms from the source that are available to atomize.
+// Part of the Conductor interface
+func (n *natsmq) Receive(ctx context.Context) <-chan atomizer.Electron {
+	electrons := make(chan atomizer.Electron)
+
+	sub, err := n.conn.Subscribe(n.subject, func(msg *nats.Msg) {
+		e, err := rpc.UnmarshalElectron(msg.Data)
+		if err != nil {
+			alog.Errorf(errors.Errorf("unable to parse electron %s", string(msg.Data)), "")
+			return
+		}
+
+		if msg.Reply != "" {
+			n.pending.Store(e.ID, msg)
+		}
+
+		select {
+		case <-ctx.Done():
+		case electrons <- e:
+			alog.Printf("electron [%s] received by conductor", e.ID)
+		}
+	})
+
+	if err != nil {
+		alog.Error(err)
+		close(electrons)
+		return electrons
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-n.ctx.Done():
+		}
+
+		_ = sub.Unsubscribe()
+		close(electrons)
+	}()
+
+	return electrons
+}
+
+// ensureReplySubscription subscribes to this conductor's inbox exactly
+// once, dispatching every result that arrives on it through router by the
+// correlation ID carried on CorrelationIDHeader.
+func (n *natsmq) ensureReplySubscription() (err error) {
+	n.replyMu.Do(func() {
+		_, err = n.conn.Subscribe(n.inbox, func(msg *nats.Msg) {
+			id := msg.Header.Get(CorrelationIDHeader)
+
+			if derr := n.router.Dispatch(n.ctx, id, msg.Data); derr != nil {
+				alog.Errorf(derr, "error while un-marshalling results for conductor inbox [%s]", n.inbox)
+			}
+		})
+	})
+
+	return err
+}
+
+// Sends electrons back out through the conductor for additional processing
+func (n *natsmq) Send(ctx context.Context, electron atomizer.Electron) (<-chan *atomizer.Properties, error) {
+	respond := make(chan *atomizer.Properties)
+
+	if err := n.ensureReplySubscription(); err != nil {
+		close(respond)
+		return respond, err
+	}
+
+	body, err := rpc.MarshalElectron(electron)
+	if err != nil {
+		alog.Errorf(err, "error while marshalling electron [%s]", electron.ID)
+		close(respond)
+		return respond, err
+	}
+
+	// Bound how long this electron waits for a result with sendTimeout,
+	// unless the caller already supplied their own deadline.
+	sendCtx := ctx
+	var cancel context.CancelFunc
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && n.sendTimeout > 0 {
+		sendCtx, cancel = context.WithTimeout(ctx, n.sendTimeout)
+	}
+
+	// Register the electron return channel prior to publishing the request
+	n.router.Register(electron.ID, respond)
+
+	msg := nats.NewMsg(n.subject)
+	msg.Reply = n.inbox
+	msg.Data = body
+	msg.Header.Set(CorrelationIDHeader, electron.ID)
+
+	if err := n.conn.PublishMsg(msg); err != nil {
+		alog.Errorf(err, "error sending electron [%s] for processing", electron.ID)
+
+		if cancel != nil {
+			cancel()
+		}
+
+		if ch, ok := n.router.Cancel(electron.ID); ok {
+			close(ch)
+		}
+
+		return respond, err
+	}
+
+	alog.Printf("sent electron [%s] for processing\n", electron.ID)
+
+	// Give up waiting once sendCtx is done; router.Dispatch removes the
+	// registration itself once a result actually arrives, so Cancel below
+	// is a no-op in that case.
+	go func() {
+		if cancel != nil {
+			defer cancel()
+		}
+
+		select {
+		case <-sendCtx.Done():
+		case <-n.ctx.Done():
+			return
+		}
+
+		if ch, ok := n.router.Cancel(electron.ID); ok {
+			close(ch)
+			alog.Errorf(sendCtx.Err(), "electron [%s] timed out waiting for a result", electron.ID)
+		}
+	}()
+
+	return respond, nil
+}
+
+// Complete mark the completion of an electron instance with applicable statistics
+func (n *natsmq) Complete(ctx context.Context, properties *atomizer.Properties) error {
+	v, ok := n.pending.Load(properties.ElectronID)
+	if !ok {
+		return nil
+	}
+	n.pending.Delete(properties.ElectronID)
+
+	original, ok := v.(*nats.Msg)
+	if !ok || original.Reply == "" {
+		return nil
+	}
+
+	body, err := rpc.MarshalProperties(properties)
+	if err != nil {
+		alog.Errorf(err, "error while marshalling properties for electron [%s]", properties.ElectronID)
+		return err
+	}
+
+	reply := nats.NewMsg(original.Reply)
+	reply.Data = body
+	reply.Header.Set(CorrelationIDHeader, properties.ElectronID)
+
+	if err := n.conn.PublishMsg(reply); err != nil {
+		alog.Errorf(err, "error publishing results for electron [%s]", properties.ElectronID)
+		return err
+	}
+
+	alog.Printf("sent results for electron [%s] to [%s]", properties.ElectronID, original.Reply)
+
+	return nil
+}
+
+func (n *natsmq) Close() {
+	// cancel out the internal context cleaning up the nats connection
+	n.cancel()
+}